@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// Supported values for Config.AzureAuthMethod.
+const (
+	authMethodClientSecret      = "client_secret"
+	authMethodClientCertificate = "client_certificate"
+	authMethodManagedIdentity   = "managed_identity"
+	authMethodWorkloadIdentity  = "workload_identity"
+	authMethodCLI               = "cli"
+	authMethodDefault           = "default"
+)
+
+// newAzureCredential builds the azcore.TokenCredential to authenticate
+// against Azure, selected via AzureAuthMethod so the updater can run with a
+// client secret, a client certificate, AKS/VM managed identity, GitHub
+// Actions OIDC workload identity, the local Azure CLI session, or the
+// standard DefaultAzureCredential fallback chain. cloudConfig is threaded
+// through so the credential authenticates against the right sovereign cloud;
+// the "cli" method is built without it and uses the Azure CLI's own session.
+func newAzureCredential(config Config, cloudConfig cloud.Configuration) (azcore.TokenCredential, error) {
+	clientOptions := azcore.ClientOptions{Cloud: cloudConfig}
+
+	switch strings.ToLower(config.AzureAuthMethod) {
+	case authMethodClientCertificate:
+		return newClientCertificateCredential(config, clientOptions)
+	case authMethodManagedIdentity:
+		opts := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOptions}
+		if config.AzureClientID != "" {
+			opts.ID = azidentity.ClientID(config.AzureClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(opts)
+	case authMethodWorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: clientOptions,
+			ClientID:      config.AzureClientID,
+			TenantID:      config.AzureTenantID,
+			TokenFilePath: config.AzureFederatedToken,
+		})
+	case authMethodCLI:
+		return azidentity.NewAzureCLICredential(nil)
+	case authMethodDefault:
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOptions})
+	case authMethodClientSecret, "":
+		return azidentity.NewClientSecretCredential(
+			config.AzureTenantID,
+			config.AzureClientID,
+			config.AzureClientSecret,
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: clientOptions},
+		)
+	default:
+		return nil, fmt.Errorf("unknown AZURE_AUTH_METHOD %q", config.AzureAuthMethod)
+	}
+}
+
+// newClientCertificateCredential loads a PEM or PFX certificate from disk
+// and builds a ClientCertificateCredential from it.
+func newClientCertificateCredential(config Config, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	data, err := os.ReadFile(config.AzureClientCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+
+	certs, key, err := azidentity.ParseCertificates(data, []byte(config.AzureClientCertPass))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	return azidentity.NewClientCertificateCredential(
+		config.AzureTenantID,
+		config.AzureClientID,
+		certs,
+		key,
+		&azidentity.ClientCertificateCredentialOptions{ClientOptions: clientOptions},
+	)
+}
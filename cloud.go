@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// Supported values for Config.AzureEnvironment.
+const (
+	azureEnvironmentPublic       = "public"
+	azureEnvironmentChina        = "china"
+	azureEnvironmentGovernment   = "government"
+	azureEnvironmentUSGovernment = "usgovernment"
+)
+
+// resolveCloudConfiguration maps the AZURE_ENVIRONMENT config value to the
+// matching cloud.Configuration, so the tool can target Azure China or US
+// Government DNS zones instead of being pinned to Azure Public Cloud.
+func resolveCloudConfiguration(environment string) (cloud.Configuration, error) {
+	switch strings.ToLower(environment) {
+	case azureEnvironmentPublic, "":
+		return cloud.AzurePublic, nil
+	case azureEnvironmentChina:
+		return cloud.AzureChina, nil
+	case azureEnvironmentGovernment, azureEnvironmentUSGovernment:
+		return cloud.AzureGovernment, nil
+	default:
+		return cloud.Configuration{}, fmt.Errorf("unknown AZURE_ENVIRONMENT %q", environment)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+)
+
+// defaultUpdateMaxRetries bounds how many times we retry a record update
+// after an ETag precondition-failure (HTTP 412) before giving up.
+const defaultUpdateMaxRetries = 5
+
+// managedIPMetadataKeyA and managedIPMetadataKeyAAAA tag a record set's
+// Metadata with the IP we last wrote for it. Identifying "our" record by an
+// in-memory previous IP alone doesn't survive a process restart, so we also
+// persist it on the Azure side and read it back on every Get.
+const (
+	managedIPMetadataKeyA    = "azure-dns-updater-ipv4"
+	managedIPMetadataKeyAAAA = "azure-dns-updater-ipv6"
+)
+
+// updateDNSRecord updates a single DNS record of the given type with the new
+// IP using a Get-modify-Update pattern: the current record set (and its
+// Etag) is fetched first, our IP is merged into it alongside any other
+// records already present, and the write is conditioned on that Etag via
+// IfMatch so a concurrent writer can't be silently clobbered. If the record
+// set doesn't exist yet, it is created with IfNoneMatch "*" so two racing
+// instances can't both create it. A 412 precondition-failure is retried
+// with backoff up to AzureUpdateMaxRetries times.
+func (u *DNSUpdater) updateDNSRecord(ctx context.Context, recordSetName string, recordType armdns.RecordType, ip string) error {
+	u.logger.Info("updating DNS record", "recordset", recordSetName, "type", recordType, "ip", ip)
+
+	ttl := int64(u.config.Interval.Seconds())
+	if ttl < 60 {
+		ttl = 60 // Minimum TTL of 60 seconds
+	}
+
+	for attempt := 0; ; attempt++ {
+		var existing armdns.RecordSetsClientGetResponse
+		err := observeAzureCall("dns.get", func() error {
+			var err error
+			existing, err = u.client.Get(ctx, u.config.AzureResourceGroup, u.config.AzureDNSZone, recordSetName, recordType, nil)
+			return err
+		})
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if errors.As(err, &respErr) && respErr.StatusCode == 404 {
+				if err := u.createDNSRecord(ctx, recordSetName, recordType, ip, ttl); err != nil {
+					return err
+				}
+				u.logger.Info("DNS record created successfully", "recordset", recordSetName, "type", recordType)
+				return nil
+			}
+			return fmt.Errorf("Azure DNS get error: %w", err)
+		}
+
+		properties := &armdns.RecordSetProperties{TTL: to.Ptr(ttl)}
+		switch recordType {
+		case armdns.RecordTypeAAAA:
+			prevIP := managedIP(existing.Properties.Metadata, managedIPMetadataKeyAAAA)
+			properties.AaaaRecords = mergeAaaaRecords(existing.Properties.AaaaRecords, prevIP, ip)
+			properties.Metadata = withManagedIP(existing.Properties.Metadata, managedIPMetadataKeyAAAA, ip)
+		default:
+			prevIP := managedIP(existing.Properties.Metadata, managedIPMetadataKeyA)
+			properties.ARecords = mergeARecords(existing.Properties.ARecords, prevIP, ip)
+			properties.Metadata = withManagedIP(existing.Properties.Metadata, managedIPMetadataKeyA, ip)
+		}
+
+		err = observeAzureCall("dns.update", func() error {
+			_, err := u.client.Update(
+				ctx,
+				u.config.AzureResourceGroup,
+				u.config.AzureDNSZone,
+				recordSetName,
+				recordType,
+				armdns.RecordSet{Properties: properties},
+				&armdns.RecordSetsClientUpdateOptions{IfMatch: existing.Etag},
+			)
+			return err
+		})
+		if err == nil {
+			u.logger.Info("DNS record updated successfully", "recordset", recordSetName, "type", recordType)
+			return nil
+		}
+
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == 412 && attempt < u.config.AzureUpdateMaxRetries {
+			backoff := retryBackoff(attempt)
+			u.logger.Debug("DNS update precondition failed, retrying", "recordset", recordSetName, "attempt", attempt+1, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			continue
+		}
+
+		return fmt.Errorf("Azure DNS update error: %w", err)
+	}
+}
+
+// createDNSRecord creates a brand new record set, failing if one was created concurrently.
+func (u *DNSUpdater) createDNSRecord(ctx context.Context, recordSetName string, recordType armdns.RecordType, ip string, ttl int64) error {
+	properties := &armdns.RecordSetProperties{TTL: to.Ptr(ttl)}
+	switch recordType {
+	case armdns.RecordTypeAAAA:
+		properties.AaaaRecords = []*armdns.AaaaRecord{{IPv6Address: &ip}}
+		properties.Metadata = withManagedIP(nil, managedIPMetadataKeyAAAA, ip)
+	default:
+		properties.ARecords = []*armdns.ARecord{{IPv4Address: &ip}}
+		properties.Metadata = withManagedIP(nil, managedIPMetadataKeyA, ip)
+	}
+
+	err := observeAzureCall("dns.create", func() error {
+		_, err := u.client.CreateOrUpdate(
+			ctx,
+			u.config.AzureResourceGroup,
+			u.config.AzureDNSZone,
+			recordSetName,
+			recordType,
+			armdns.RecordSet{Properties: properties},
+			&armdns.RecordSetsClientCreateOrUpdateOptions{IfNoneMatch: to.Ptr("*")},
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Azure DNS create error: %w", err)
+	}
+	return nil
+}
+
+// managedIP reads back the IP we tagged a record set with under key, or ""
+// if the record set carries no such marker (e.g. it predates this feature,
+// or was never written by us).
+func managedIP(metadata map[string]*string, key string) string {
+	if v, ok := metadata[key]; ok && v != nil {
+		return *v
+	}
+	return ""
+}
+
+// withManagedIP returns a copy of metadata with key set to ip, so the
+// managed-IP marker survives alongside whatever other metadata a record set
+// already carries. It is stored on the Azure side (rather than kept only in
+// u.prevIPv4/u.prevIPv6) so that mergeARecords/mergeAaaaRecords can still
+// identify and evict our stale entry after a process restart.
+func withManagedIP(metadata map[string]*string, key, ip string) map[string]*string {
+	merged := make(map[string]*string, len(metadata)+1)
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	merged[key] = &ip
+	return merged
+}
+
+// mergeARecords replaces our own managed entry (identified by prevIP, the
+// last value we wrote) with the new IP, leaving records added by other tools
+// untouched. It also dedupes against newIP in case it's already present.
+func mergeARecords(existing []*armdns.ARecord, prevIP, newIP string) []*armdns.ARecord {
+	merged := make([]*armdns.ARecord, 0, len(existing)+1)
+	for _, r := range existing {
+		if r.IPv4Address != nil && (*r.IPv4Address == newIP || (prevIP != "" && *r.IPv4Address == prevIP)) {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return append(merged, &armdns.ARecord{IPv4Address: &newIP})
+}
+
+// mergeAaaaRecords is the AAAA counterpart of mergeARecords.
+func mergeAaaaRecords(existing []*armdns.AaaaRecord, prevIP, newIP string) []*armdns.AaaaRecord {
+	merged := make([]*armdns.AaaaRecord, 0, len(existing)+1)
+	for _, r := range existing {
+		if r.IPv6Address != nil && (*r.IPv6Address == newIP || (prevIP != "" && *r.IPv6Address == prevIP)) {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return append(merged, &armdns.AaaaRecord{IPv6Address: &newIP})
+}
+
+// retryBackoff returns an exponential backoff duration with jitter for the given attempt.
+func retryBackoff(attempt int) time.Duration {
+	base := 500 * time.Millisecond
+	backoff := base * time.Duration(1<<uint(attempt))
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	return backoff/2 + jitter
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// health tracks readiness and liveness across update cycles for the
+// /readyz and /healthz endpoints.
+var health = &healthTracker{}
+
+// healthTracker is safe for concurrent use: update cycles report their
+// outcome from the updater's goroutine while the HTTP server reads it from
+// request-handling goroutines.
+type healthTracker struct {
+	mu                  sync.Mutex
+	ready               bool
+	lastSuccess         time.Time
+	consecutiveFailures int
+}
+
+// recordCycleResult records the outcome of one checkAndUpdate call.
+func (h *healthTracker) recordCycleResult(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil {
+		h.ready = true
+		h.lastSuccess = time.Now()
+		h.consecutiveFailures = 0
+		return
+	}
+	h.consecutiveFailures++
+}
+
+// isReady reports whether the first successful update cycle has completed.
+func (h *healthTracker) isReady() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ready
+}
+
+// isHealthy reports whether the last maxConsecutiveFailures update cycles
+// haven't all errored.
+func (h *healthTracker) isHealthy(maxConsecutiveFailures int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.consecutiveFailures < maxConsecutiveFailures
+}
+
+func (h *healthTracker) secondsSinceLastSuccess() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lastSuccess.IsZero() {
+		return -1
+	}
+	return time.Since(h.lastSuccess).Seconds()
+}
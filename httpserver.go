@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/exp/slog"
+)
+
+// defaultHealthWindow is how many trailing update cycles must all have
+// errored before /healthz starts failing.
+const defaultHealthWindow = 3
+
+// startHealthServer starts the optional embedded HTTP server exposing
+// /healthz, /readyz and /metrics. It runs until ctx is cancelled. bindAddr
+// empty disables the server entirely.
+func startHealthServer(ctx context.Context, logger *slog.Logger, bindAddr string, healthWindow int) {
+	if bindAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !health.isReady() {
+			http.Error(w, "not ready: no successful update yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !health.isHealthy(healthWindow) {
+			http.Error(w, "unhealthy: last update cycles all failed", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: bindAddr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logger.Info("starting health/metrics server", "addr", bindAddr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Error("health/metrics server stopped unexpectedly", "error", err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultIPResolverQuorum  = 2
+	defaultIPResolverTimeout = 5 * time.Second
+)
+
+// ResolverConfig declares a single IP-resolver source, as loaded from TOML.
+// Type selects between an HTTP-based lookup service and a DNS-based one;
+// the remaining fields are interpreted according to Type.
+type ResolverConfig struct {
+	Type   string `koanf:"type"`   // "http" or "dns"
+	Name   string `koanf:"name"`   // human-readable label, used in logs
+	URL    string `koanf:"url"`    // http: the URL to GET
+	Server string `koanf:"server"` // dns: "host:port" of the resolver to query
+	Query  string `koanf:"query"`  // dns: the name to query
+	Record string `koanf:"record"` // dns: "A", "AAAA" or "TXT"
+}
+
+// defaultIPv4Resolvers mirrors the set of well-known IP lookup services used
+// by ecosystem tools (e.g. ddclient, lego), mixing HTTP and DNS-based
+// sources so a single misbehaving provider can't skew the result.
+var defaultIPv4Resolvers = []ResolverConfig{
+	{Type: "http", Name: "ifconfig.me", URL: "https://ifconfig.me"},
+	{Type: "http", Name: "ipify", URL: "https://api.ipify.org"},
+	{Type: "http", Name: "icanhazip", URL: "https://ipv4.icanhazip.com"},
+	{Type: "dns", Name: "opendns", Server: "resolver1.opendns.com:53", Query: "myip.opendns.com.", Record: "A"},
+	{Type: "dns", Name: "google", Server: "ns1.google.com:53", Query: "o-o.myaddr.l.google.com.", Record: "TXT"},
+}
+
+var defaultIPv6Resolvers = []ResolverConfig{
+	{Type: "http", Name: "ifconfig.me", URL: "https://ifconfig.me"},
+	{Type: "http", Name: "ipify", URL: "https://api64.ipify.org"},
+	{Type: "http", Name: "icanhazip", URL: "https://ipv6.icanhazip.com"},
+}
+
+// IPResolver resolves the caller's current public IP address.
+type IPResolver interface {
+	Resolve(ctx context.Context) (string, error)
+}
+
+// newMultiResolver builds the resolver used for one address family from its
+// configured sources. network ("tcp4" or "tcp6") is the address family of
+// the pool being built, and is passed down to each HTTP resolver explicitly
+// rather than guessed from its URL.
+func newMultiResolver(configs []ResolverConfig, network string, quorum int, timeout time.Duration) IPResolver {
+	resolvers := make([]IPResolver, 0, len(configs))
+	for _, c := range configs {
+		r, err := newResolver(c, network, timeout)
+		if err != nil {
+			continue
+		}
+		resolvers = append(resolvers, r)
+	}
+	return &MultiResolver{resolvers: resolvers, quorum: quorum, timeout: timeout}
+}
+
+func newResolver(c ResolverConfig, network string, timeout time.Duration) (IPResolver, error) {
+	switch strings.ToLower(c.Type) {
+	case "dns":
+		return &dnsResolver{
+			name:    c.Name,
+			server:  c.Server,
+			query:   c.Query,
+			record:  strings.ToUpper(c.Record),
+			network: dnsNetworkFor(network),
+			timeout: timeout,
+		}, nil
+	case "http", "":
+		return &httpResolver{name: c.Name, url: c.URL, client: newAddressFamilyClient(network, timeout)}, nil
+	default:
+		return nil, fmt.Errorf("unknown resolver type %q", c.Type)
+	}
+}
+
+// MultiResolver queries several IPResolvers concurrently and only accepts a
+// value once at least `quorum` of them agree, defending against any single
+// upstream returning a stale or incorrect address.
+type MultiResolver struct {
+	resolvers []IPResolver
+	quorum    int
+	timeout   time.Duration
+}
+
+func (m *MultiResolver) Resolve(ctx context.Context) (string, error) {
+	if len(m.resolvers) == 0 {
+		return "", fmt.Errorf("no IP resolvers configured")
+	}
+
+	type result struct {
+		ip  string
+		err error
+	}
+	results := make([]result, len(m.resolvers))
+
+	var wg sync.WaitGroup
+	for i, r := range m.resolvers {
+		wg.Add(1)
+		go func(i int, r IPResolver) {
+			defer wg.Done()
+			rctx, cancel := context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+			ip, err := r.Resolve(rctx)
+			results[i] = result{ip: ip, err: err}
+		}(i, r)
+	}
+	wg.Wait()
+
+	votes := make(map[string]int)
+	for _, res := range results {
+		if res.err == nil && res.ip != "" {
+			votes[res.ip]++
+		}
+	}
+
+	for ip, count := range votes {
+		if count >= m.quorum {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("no quorum (%d) reached among %d resolvers: %v", m.quorum, len(m.resolvers), votes)
+}
+
+// httpResolver resolves the public IP by GETing a plain-text IP echo service.
+type httpResolver struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func (h *httpResolver) Resolve(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: unexpected status code: %d", h.name, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// newAddressFamilyClient returns an HTTP client whose connections are
+// restricted to the given network family ("tcp4" or "tcp6"), so that
+// address-family-specific IP lookup services always resolve over the
+// intended stack.
+func newAddressFamilyClient(network string, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+// dnsNetworkFor maps an HTTP-style address family ("tcp4"/"tcp6") to the
+// equivalent dns.Client network ("udp4"/"udp6"), so a DNS-based resolver is
+// bound to the same socket family as its HTTP siblings in the same pool.
+func dnsNetworkFor(network string) string {
+	if network == "tcp6" {
+		return "udp6"
+	}
+	return "udp4"
+}
+
+// dnsResolver resolves the public IP by querying a well-known DNS-based
+// "what is my IP" service, e.g. OpenDNS's `myip.opendns.com` or Google's
+// `o-o.myaddr.l.google.com` TXT trick.
+type dnsResolver struct {
+	name    string
+	server  string
+	query   string
+	record  string // "A", "AAAA" or "TXT"
+	network string // "udp4" or "udp6"
+	timeout time.Duration
+}
+
+func (d *dnsResolver) Resolve(ctx context.Context) (string, error) {
+	qtype := dns.TypeA
+	switch d.record {
+	case "AAAA":
+		qtype = dns.TypeAAAA
+	case "TXT":
+		qtype = dns.TypeTXT
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(d.query, qtype)
+
+	client := &dns.Client{Net: d.network, Timeout: d.timeout}
+
+	resp, _, err := client.ExchangeContext(ctx, msg, d.server)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", d.name, err)
+	}
+	if len(resp.Answer) == 0 {
+		return "", fmt.Errorf("%s: no answer for %s", d.name, d.query)
+	}
+
+	for _, rr := range resp.Answer {
+		switch rec := rr.(type) {
+		case *dns.A:
+			return rec.A.String(), nil
+		case *dns.AAAA:
+			return rec.AAAA.String(), nil
+		case *dns.TXT:
+			if len(rec.Txt) > 0 {
+				return strings.Trim(rec.Txt[0], `"`), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s: no usable record in answer for %s", d.name, d.query)
+}
@@ -3,17 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
 	"github.com/knadh/koanf/parsers/toml"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
@@ -21,24 +21,68 @@ import (
 	"golang.org/x/exp/slog"
 )
 
+// DNSRecordConfig describes a single managed DNS record and which record
+// types should be kept in sync with our public address(es).
+type DNSRecordConfig struct {
+	Name    string   `koanf:"name"`
+	Types   []string `koanf:"types"`
+	Private bool     `koanf:"private"`
+}
+
+// WantsA reports whether this record should receive an A (IPv4) update.
+func (r DNSRecordConfig) WantsA() bool {
+	return containsFold(r.Types, "A")
+}
+
+// WantsAAAA reports whether this record should receive an AAAA (IPv6) update.
+func (r DNSRecordConfig) WantsAAAA() bool {
+	return containsFold(r.Types, "AAAA")
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
 // Config holds all application configuration
 type Config struct {
-	Interval          time.Duration
-	AzureTenantID     string
-	AzureClientID     string
-	AzureClientSecret string
-	AzureSubID        string
-	AzureResourceGroup string
-	AzureDNSZone      string
-	AzureDNSRecords   []string
+	Interval              time.Duration
+	AzureTenantID         string
+	AzureClientID         string
+	AzureClientSecret     string
+	AzureSubID            string
+	AzureResourceGroup    string
+	AzureDNSZone          string
+	AzureDNSRecords       []DNSRecordConfig
+	AzureUpdateMaxRetries int
+	AzureAuthMethod       string
+	AzureClientCertPath   string
+	AzureClientCertPass   string
+	AzureFederatedToken   string
+	AzureEnvironment      string
+	AzurePrivateZone      bool
+	IPv4Resolvers         []ResolverConfig
+	IPv6Resolvers         []ResolverConfig
+	IPResolverQuorum      int
+	IPResolverTimeout     time.Duration
+	HTTPBindAddr          string
+	HTTPHealthWindow      int
 }
 
 // DNSUpdater manages the DNS update process
 type DNSUpdater struct {
-	config Config
-	logger *slog.Logger
-	client *armdns.RecordSetsClient
-	prevIP string
+	config        Config
+	logger        *slog.Logger
+	client        *armdns.RecordSetsClient
+	privateClient *armprivatedns.RecordSetsClient
+	ipv4Resolver  IPResolver
+	ipv6Resolver  IPResolver
+	prevIPv4      string
+	prevIPv6      string
 }
 
 func main() {
@@ -53,28 +97,43 @@ func main() {
 		os.Exit(1)
 	}
 
+	cloudConfig, err := resolveCloudConfiguration(config.AzureEnvironment)
+	if err != nil {
+		logger.Error("failed to resolve Azure cloud environment", "error", err)
+		os.Exit(1)
+	}
+
 	// Create Azure DNS client
-	cred, err := azidentity.NewClientSecretCredential(
-		config.AzureTenantID,
-		config.AzureClientID,
-		config.AzureClientSecret,
-		nil,
-	)
+	cred, err := newAzureCredential(config, cloudConfig)
 	if err != nil {
 		logger.Error("failed to create Azure credentials", "error", err)
 		os.Exit(1)
 	}
 
-	client, err := armdns.NewRecordSetsClient(config.AzureSubID, cred, nil)
+	armOptions := &arm.ClientOptions{ClientOptions: azcore.ClientOptions{Cloud: cloudConfig}}
+
+	client, err := armdns.NewRecordSetsClient(config.AzureSubID, cred, armOptions)
 	if err != nil {
 		logger.Error("failed to create Azure DNS client", "error", err)
 		os.Exit(1)
 	}
 
+	var privateClient *armprivatedns.RecordSetsClient
+	if hasPrivateRecords(config.AzureDNSRecords) {
+		privateClient, err = armprivatedns.NewRecordSetsClient(config.AzureSubID, cred, armOptions)
+		if err != nil {
+			logger.Error("failed to create Azure Private DNS client", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	updater := &DNSUpdater{
-		config: config,
-		logger: logger,
-		client: client,
+		config:        config,
+		logger:        logger,
+		client:        client,
+		privateClient: privateClient,
+		ipv4Resolver:  newMultiResolver(config.IPv4Resolvers, "tcp4", config.IPResolverQuorum, config.IPResolverTimeout),
+		ipv6Resolver:  newMultiResolver(config.IPv6Resolvers, "tcp6", config.IPResolverQuorum, config.IPResolverTimeout),
 	}
 
 	// Setup context with cancellation for graceful shutdown
@@ -90,6 +149,8 @@ func main() {
 		cancel()
 	}()
 
+	go startHealthServer(ctx, logger, config.HTTPBindAddr, config.HTTPHealthWindow)
+
 	// Run the updater
 	if err := updater.Run(ctx); err != nil {
 		logger.Error("updater failed", "error", err)
@@ -120,28 +181,128 @@ func loadConfig(logger *slog.Logger) (Config, error) {
 		return Config{}, fmt.Errorf("error parsing interval: %w", err)
 	}
 
-	// Parse DNS records
-	dnsRecords := strings.Split(k.String("AZURE_DNS_RECORDS"), ",")
-	// Filter out empty strings
-	var records []string
-	for _, r := range dnsRecords {
-		if r = strings.TrimSpace(r); r != "" {
-			records = append(records, r)
+	privateZone := k.Bool("AZURE_PRIVATE_ZONE")
+
+	records, err := loadDNSRecords(k, privateZone)
+	if err != nil {
+		return Config{}, fmt.Errorf("error loading DNS record config: %w", err)
+	}
+
+	maxRetries := defaultUpdateMaxRetries
+	if v := k.String("AZURE_UPDATE_MAX_RETRIES"); v != "" {
+		maxRetries, err = strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing AZURE_UPDATE_MAX_RETRIES: %w", err)
+		}
+	}
+
+	ipv4Resolvers, ipv6Resolvers, err := loadResolvers(k)
+	if err != nil {
+		return Config{}, fmt.Errorf("error loading IP resolver config: %w", err)
+	}
+
+	quorum := defaultIPResolverQuorum
+	if v := k.String("IP_RESOLVER_QUORUM"); v != "" {
+		quorum, err = strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing IP_RESOLVER_QUORUM: %w", err)
+		}
+	}
+
+	resolverTimeout := defaultIPResolverTimeout
+	if v := k.String("IP_RESOLVER_TIMEOUT"); v != "" {
+		resolverTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing IP_RESOLVER_TIMEOUT: %w", err)
+		}
+	}
+
+	federatedToken := k.String("AZURE_FEDERATED_TOKEN_FILE")
+	if federatedToken == "" {
+		federatedToken = k.String("AZURE_OIDC_TOKEN_FILE_PATH")
+	}
+
+	healthWindow := defaultHealthWindow
+	if v := k.String("HTTP_HEALTH_WINDOW"); v != "" {
+		healthWindow, err = strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing HTTP_HEALTH_WINDOW: %w", err)
 		}
 	}
 
 	return Config{
-		Interval:          interval,
-		AzureTenantID:     k.String("AZURE_TENANT_ID"),
-		AzureClientID:     k.String("AZURE_CLIENT_ID"),
-		AzureClientSecret: k.String("AZURE_CLIENT_SECRET"),
-		AzureSubID:        k.String("AZURE_SUBSCRIPTION_ID"),
-		AzureResourceGroup: k.String("AZURE_RESOURCE_GROUP"),
-		AzureDNSZone:      k.String("AZURE_DNS_ZONE"),
-		AzureDNSRecords:   records,
+		Interval:              interval,
+		AzureTenantID:         k.String("AZURE_TENANT_ID"),
+		AzureClientID:         k.String("AZURE_CLIENT_ID"),
+		AzureClientSecret:     k.String("AZURE_CLIENT_SECRET"),
+		AzureSubID:            k.String("AZURE_SUBSCRIPTION_ID"),
+		AzureResourceGroup:    k.String("AZURE_RESOURCE_GROUP"),
+		AzureDNSZone:          k.String("AZURE_DNS_ZONE"),
+		AzureDNSRecords:       records,
+		AzureUpdateMaxRetries: maxRetries,
+		AzureAuthMethod:       k.String("AZURE_AUTH_METHOD"),
+		AzureClientCertPath:   k.String("AZURE_CLIENT_CERTIFICATE_PATH"),
+		AzureClientCertPass:   k.String("AZURE_CLIENT_CERTIFICATE_PASSWORD"),
+		AzureFederatedToken:   federatedToken,
+		AzureEnvironment:      k.String("AZURE_ENVIRONMENT"),
+		AzurePrivateZone:      privateZone,
+		IPv4Resolvers:         ipv4Resolvers,
+		IPv6Resolvers:         ipv6Resolvers,
+		IPResolverQuorum:      quorum,
+		IPResolverTimeout:     resolverTimeout,
+		HTTPBindAddr:          k.String("HTTP_BIND_ADDR"),
+		HTTPHealthWindow:      healthWindow,
 	}, nil
 }
 
+// loadDNSRecords resolves the set of managed records and their record types.
+// Records are preferably declared as a `[[records]]` TOML array (so each
+// record can independently opt into A and/or AAAA, and into the private
+// zone via "private"), falling back to the legacy AZURE_DNS_RECORDS
+// comma-separated env var, which only ever managed public A records;
+// defaultPrivate seeds that fallback from AZURE_PRIVATE_ZONE.
+func loadDNSRecords(k *koanf.Koanf, defaultPrivate bool) ([]DNSRecordConfig, error) {
+	if k.Exists("records") {
+		var records []DNSRecordConfig
+		if err := k.Unmarshal("records", &records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+
+	var records []DNSRecordConfig
+	for _, r := range strings.Split(k.String("AZURE_DNS_RECORDS"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			records = append(records, DNSRecordConfig{Name: r, Types: []string{"A"}, Private: defaultPrivate})
+		}
+	}
+	return records, nil
+}
+
+// loadResolvers resolves the configured IP-resolver set for each address
+// family from `[[resolvers_v4]]`/`[[resolvers_v6]]` TOML arrays, falling
+// back to a sane built-in multi-provider default so the updater works with
+// zero resolver configuration.
+func loadResolvers(k *koanf.Koanf) (ipv4, ipv6 []ResolverConfig, err error) {
+	if k.Exists("resolvers_v4") {
+		if err := k.Unmarshal("resolvers_v4", &ipv4); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		ipv4 = defaultIPv4Resolvers
+	}
+
+	if k.Exists("resolvers_v6") {
+		if err := k.Unmarshal("resolvers_v6", &ipv6); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		ipv6 = defaultIPv6Resolvers
+	}
+
+	return ipv4, ipv6, nil
+}
+
 // Run starts the DNS updater loop
 func (u *DNSUpdater) Run(ctx context.Context) error {
 	ticker := time.NewTicker(u.config.Interval)
@@ -150,6 +311,9 @@ func (u *DNSUpdater) Run(ctx context.Context) error {
 	// Do an initial update
 	if err := u.checkAndUpdate(ctx); err != nil {
 		u.logger.Error("initial update failed", "error", err)
+		health.recordCycleResult(err)
+	} else {
+		health.recordCycleResult(nil)
 	}
 
 	for {
@@ -157,95 +321,89 @@ func (u *DNSUpdater) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			if err := u.checkAndUpdate(ctx); err != nil {
+			err := u.checkAndUpdate(ctx)
+			if err != nil {
 				u.logger.Error("update failed", "error", err)
 			}
+			health.recordCycleResult(err)
 		}
 	}
 }
 
-// checkAndUpdate checks the current IP and updates DNS if needed
+// checkAndUpdate checks the current IPv4/IPv6 addresses and updates DNS if needed
 func (u *DNSUpdater) checkAndUpdate(ctx context.Context) error {
-	ip, err := u.resolvePublicIP(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to resolve public IP: %w", err)
-	}
+	wantIPv4, wantIPv6 := u.recordTypesInUse()
 
-	// If IP hasn't changed, do nothing
-	if ip == u.prevIP {
-		u.logger.Debug("IP unchanged", "ip", ip)
-		return nil
-	}
+	var ipv4, ipv6 string
+	var err error
 
-	u.logger.Info("IP changed", "ip", ip, "previous", u.prevIP)
-	
-	// Update all DNS records
-	for _, recordSet := range u.config.AzureDNSRecords {
-		if err := u.updateDNSRecord(ctx, recordSet, ip); err != nil {
-			u.prevIP = "" // Reset prevIP to force retry on next run
-			return fmt.Errorf("failed to update DNS record %s: %w", recordSet, err)
+	if wantIPv4 {
+		ipv4, err = u.ipv4Resolver.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve public IPv4: %w", err)
+		}
+	}
+	if wantIPv6 {
+		ipv6, err = u.ipv6Resolver.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve public IPv6: %w", err)
 		}
 	}
 
-	u.prevIP = ip
-	return nil
-}
+	ipv4Changed := wantIPv4 && ipv4 != u.prevIPv4
+	ipv6Changed := wantIPv6 && ipv6 != u.prevIPv6
 
-// resolvePublicIP gets the current public IP address
-func (u *DNSUpdater) resolvePublicIP(ctx context.Context) (string, error) {
-	u.logger.Debug("checking for public IP")
-	
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://ifconfig.me", nil)
-	if err != nil {
-		return "", err
+	if !ipv4Changed && !ipv6Changed {
+		u.logger.Debug("IP unchanged", "ipv4", ipv4, "ipv6", ipv6)
+		return nil
 	}
-	
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
+
+	u.logger.Info("IP changed", "ipv4", ipv4, "previousIPv4", u.prevIPv4, "ipv6", ipv6, "previousIPv6", u.prevIPv6)
+
+	if ipv4Changed {
+		lastDetectedIPTimestamp.WithLabelValues("ipv4").SetToCurrentTime()
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if ipv6Changed {
+		lastDetectedIPTimestamp.WithLabelValues("ipv6").SetToCurrentTime()
 	}
-	
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+
+	for _, record := range u.config.AzureDNSRecords {
+		if ipv4Changed && record.WantsA() {
+			if err := u.updateRecord(ctx, record, armdns.RecordTypeA, ipv4); err != nil {
+				u.prevIPv4 = "" // Reset prevIPv4 to force retry on next run
+				updatesTotal.WithLabelValues("error", record.Name).Inc()
+				return fmt.Errorf("failed to update A record %s: %w", record.Name, err)
+			}
+			updatesTotal.WithLabelValues("success", record.Name).Inc()
+		}
+		if ipv6Changed && record.WantsAAAA() {
+			if err := u.updateRecord(ctx, record, armdns.RecordTypeAAAA, ipv6); err != nil {
+				u.prevIPv6 = "" // Reset prevIPv6 to force retry on next run
+				updatesTotal.WithLabelValues("error", record.Name).Inc()
+				return fmt.Errorf("failed to update AAAA record %s: %w", record.Name, err)
+			}
+			updatesTotal.WithLabelValues("success", record.Name).Inc()
+		}
 	}
-	
-	return string(body), nil
-}
 
-// updateDNSRecord updates a single DNS record with the new IP
-func (u *DNSUpdater) updateDNSRecord(ctx context.Context, recordSetName, ip string) error {
-	u.logger.Info("updating DNS record", "recordset", recordSetName, "ip", ip)
-	
-	ttl := int64(u.config.Interval.Seconds())
-	if ttl < 60 {
-		ttl = 60 // Minimum TTL of 60 seconds
-	}
-	
-	_, err := u.client.Update(
-		ctx,
-		u.config.AzureResourceGroup,
-		u.config.AzureDNSZone,
-		recordSetName,
-		armdns.RecordTypeA,
-		armdns.RecordSet{
-			Properties: &armdns.RecordSetProperties{
-				ARecords: []*armdns.ARecord{{IPv4Address: &ip}},
-				TTL:      to.Ptr(ttl),
-			},
-		},
-		&armdns.RecordSetsClientUpdateOptions{},
-	)
-	
-	if err != nil {
-		return fmt.Errorf("Azure DNS update error: %w", err)
+	if ipv4Changed {
+		u.prevIPv4 = ipv4
+	}
+	if ipv6Changed {
+		u.prevIPv6 = ipv6
 	}
-	
-	u.logger.Info("DNS record updated successfully", "recordset", recordSetName)
 	return nil
 }
+
+// recordTypesInUse reports whether any configured record wants A and/or AAAA updates.
+func (u *DNSUpdater) recordTypesInUse() (wantIPv4, wantIPv6 bool) {
+	for _, record := range u.config.AzureDNSRecords {
+		if record.WantsA() {
+			wantIPv4 = true
+		}
+		if record.WantsAAAA() {
+			wantIPv6 = true
+		}
+	}
+	return wantIPv4, wantIPv6
+}
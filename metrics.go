@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	updatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "updates_total",
+		Help: "Total number of DNS record update attempts, by result and record.",
+	}, []string{"result", "record"})
+
+	lastDetectedIPTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "last_detected_ip_timestamp_seconds",
+		Help: "Unix timestamp at which a change of the public IP was last detected, by address family.",
+	}, []string{"family"})
+
+	azureAPIDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "azure_api_call_duration_seconds",
+		Help:    "Duration of Azure DNS API calls.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	secondsSinceLastSuccess = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "seconds_since_last_successful_update",
+		Help: "Seconds elapsed since the last successful update cycle, or -1 if there hasn't been one yet.",
+	}, func() float64 { return health.secondsSinceLastSuccess() })
+)
+
+func init() {
+	prometheus.MustRegister(updatesTotal, lastDetectedIPTimestamp, azureAPIDuration, secondsSinceLastSuccess)
+}
+
+// observeAzureCall records the duration of an Azure DNS API call under the
+// given operation label.
+func observeAzureCall(operation string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	azureAPIDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	return err
+}
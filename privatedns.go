@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+// hasPrivateRecords reports whether any configured record targets the
+// private DNS zone, so callers only pay for a privatedns client when needed.
+func hasPrivateRecords(records []DNSRecordConfig) bool {
+	for _, r := range records {
+		if r.Private {
+			return true
+		}
+	}
+	return false
+}
+
+// updateRecord dispatches a record update to the public or private DNS zone
+// client depending on the record's configuration.
+func (u *DNSUpdater) updateRecord(ctx context.Context, record DNSRecordConfig, recordType armdns.RecordType, ip string) error {
+	if record.Private {
+		return u.updatePrivateDNSRecord(ctx, record.Name, privateRecordType(recordType), ip)
+	}
+	return u.updateDNSRecord(ctx, record.Name, recordType, ip)
+}
+
+func privateRecordType(t armdns.RecordType) armprivatedns.RecordType {
+	if t == armdns.RecordTypeAAAA {
+		return armprivatedns.RecordTypeAAAA
+	}
+	return armprivatedns.RecordTypeA
+}
+
+// updatePrivateDNSRecord is the armprivatedns counterpart of updateDNSRecord:
+// the same Get-modify-write dance conditioned on the Etag via IfMatch, against
+// a private DNS zone. The write here is a CreateOrUpdate rather than an Update.
+func (u *DNSUpdater) updatePrivateDNSRecord(ctx context.Context, recordSetName string, recordType armprivatedns.RecordType, ip string) error {
+	u.logger.Info("updating private DNS record", "recordset", recordSetName, "type", recordType, "ip", ip)
+
+	ttl := int64(u.config.Interval.Seconds())
+	if ttl < 60 {
+		ttl = 60 // Minimum TTL of 60 seconds
+	}
+
+	for attempt := 0; ; attempt++ {
+		var existing armprivatedns.RecordSetsClientGetResponse
+		err := observeAzureCall("privatedns.get", func() error {
+			var err error
+			existing, err = u.privateClient.Get(ctx, u.config.AzureResourceGroup, u.config.AzureDNSZone, recordType, recordSetName, nil)
+			return err
+		})
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if errors.As(err, &respErr) && respErr.StatusCode == 404 {
+				if err := u.createPrivateDNSRecord(ctx, recordSetName, recordType, ip, ttl); err != nil {
+					return err
+				}
+				u.logger.Info("private DNS record created successfully", "recordset", recordSetName, "type", recordType)
+				return nil
+			}
+			return fmt.Errorf("Azure Private DNS get error: %w", err)
+		}
+
+		properties := &armprivatedns.RecordSetProperties{TTL: to.Ptr(ttl)}
+		switch recordType {
+		case armprivatedns.RecordTypeAAAA:
+			prevIP := managedPrivateIP(existing.Properties.Metadata, managedIPMetadataKeyAAAA)
+			properties.AaaaRecords = mergePrivateAaaaRecords(existing.Properties.AaaaRecords, prevIP, ip)
+			properties.Metadata = withManagedPrivateIP(existing.Properties.Metadata, managedIPMetadataKeyAAAA, ip)
+		default:
+			prevIP := managedPrivateIP(existing.Properties.Metadata, managedIPMetadataKeyA)
+			properties.ARecords = mergePrivateARecords(existing.Properties.ARecords, prevIP, ip)
+			properties.Metadata = withManagedPrivateIP(existing.Properties.Metadata, managedIPMetadataKeyA, ip)
+		}
+
+		err = observeAzureCall("privatedns.update", func() error {
+			_, err := u.privateClient.CreateOrUpdate(
+				ctx,
+				u.config.AzureResourceGroup,
+				u.config.AzureDNSZone,
+				recordType,
+				recordSetName,
+				armprivatedns.RecordSet{Properties: properties},
+				&armprivatedns.RecordSetsClientCreateOrUpdateOptions{IfMatch: existing.Etag},
+			)
+			return err
+		})
+		if err == nil {
+			u.logger.Info("private DNS record updated successfully", "recordset", recordSetName, "type", recordType)
+			return nil
+		}
+
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == 412 && attempt < u.config.AzureUpdateMaxRetries {
+			backoff := retryBackoff(attempt)
+			u.logger.Debug("private DNS update precondition failed, retrying", "recordset", recordSetName, "attempt", attempt+1, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			continue
+		}
+
+		return fmt.Errorf("Azure Private DNS update error: %w", err)
+	}
+}
+
+func (u *DNSUpdater) createPrivateDNSRecord(ctx context.Context, recordSetName string, recordType armprivatedns.RecordType, ip string, ttl int64) error {
+	properties := &armprivatedns.RecordSetProperties{TTL: to.Ptr(ttl)}
+	switch recordType {
+	case armprivatedns.RecordTypeAAAA:
+		properties.AaaaRecords = []*armprivatedns.AaaaRecord{{IPv6Address: &ip}}
+		properties.Metadata = withManagedPrivateIP(nil, managedIPMetadataKeyAAAA, ip)
+	default:
+		properties.ARecords = []*armprivatedns.ARecord{{IPv4Address: &ip}}
+		properties.Metadata = withManagedPrivateIP(nil, managedIPMetadataKeyA, ip)
+	}
+
+	err := observeAzureCall("privatedns.create", func() error {
+		_, err := u.privateClient.CreateOrUpdate(
+			ctx,
+			u.config.AzureResourceGroup,
+			u.config.AzureDNSZone,
+			recordType,
+			recordSetName,
+			armprivatedns.RecordSet{Properties: properties},
+			&armprivatedns.RecordSetsClientCreateOrUpdateOptions{IfNoneMatch: to.Ptr("*")},
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Azure Private DNS create error: %w", err)
+	}
+	return nil
+}
+
+// managedPrivateIP and withManagedPrivateIP are the armprivatedns
+// counterparts of managedIP/withManagedIP in dnsrecord.go, sharing the same
+// managedIPMetadataKeyA/managedIPMetadataKeyAAAA marker keys so the record
+// set's own Metadata (rather than only u.prevIPv4/u.prevIPv6) identifies
+// which entry is ours across a process restart.
+func managedPrivateIP(metadata map[string]*string, key string) string {
+	if v, ok := metadata[key]; ok && v != nil {
+		return *v
+	}
+	return ""
+}
+
+func withManagedPrivateIP(metadata map[string]*string, key, ip string) map[string]*string {
+	merged := make(map[string]*string, len(metadata)+1)
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	merged[key] = &ip
+	return merged
+}
+
+// mergePrivateARecords replaces our own managed entry (identified by
+// prevIP, the last value we wrote) with the new IP, leaving records added
+// by other tools untouched. It also dedupes against newIP in case it's
+// already present.
+func mergePrivateARecords(existing []*armprivatedns.ARecord, prevIP, newIP string) []*armprivatedns.ARecord {
+	merged := make([]*armprivatedns.ARecord, 0, len(existing)+1)
+	for _, r := range existing {
+		if r.IPv4Address != nil && (*r.IPv4Address == newIP || (prevIP != "" && *r.IPv4Address == prevIP)) {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return append(merged, &armprivatedns.ARecord{IPv4Address: &newIP})
+}
+
+// mergePrivateAaaaRecords is the AAAA counterpart of mergePrivateARecords.
+func mergePrivateAaaaRecords(existing []*armprivatedns.AaaaRecord, prevIP, newIP string) []*armprivatedns.AaaaRecord {
+	merged := make([]*armprivatedns.AaaaRecord, 0, len(existing)+1)
+	for _, r := range existing {
+		if r.IPv6Address != nil && (*r.IPv6Address == newIP || (prevIP != "" && *r.IPv6Address == prevIP)) {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return append(merged, &armprivatedns.AaaaRecord{IPv6Address: &newIP})
+}